@@ -0,0 +1,46 @@
+package maintest
+
+import "testing"
+
+func TestChooseCoverageDir(t *testing.T) {
+	cases := []struct {
+		name            string
+		binDir          string
+		gocoverdir      string
+		nativeSupported bool
+		wantDir         string
+		wantNative      bool
+	}{
+		{"native merge available and gocoverdir set", "/tmp/bin", "/tmp/gocover", true, "/tmp/gocover", true},
+		{"native merge supported but no gocoverdir", "/tmp/bin", "", true, "/tmp/bin/.coverage", false},
+		{"gocoverdir set but toolchain too old", "/tmp/bin", "/tmp/gocover", false, "/tmp/bin/.coverage", false},
+		{"neither available", "/tmp/bin", "", false, "/tmp/bin/.coverage", false},
+	}
+	for _, c := range cases {
+		dir, native := chooseCoverageDir(c.binDir, c.gocoverdir, c.nativeSupported)
+		if dir != c.wantDir || native != c.wantNative {
+			t.Errorf("%s: chooseCoverageDir(%q, %q, %v) = (%q, %v), want (%q, %v)",
+				c.name, c.binDir, c.gocoverdir, c.nativeSupported, dir, native, c.wantDir, c.wantNative)
+		}
+	}
+}
+
+func TestGoVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		v     string
+		minor int
+		want  bool
+	}{
+		{"go1.21.3", 20, true},
+		{"go1.20", 20, true},
+		{"go1.19.5", 20, false},
+		{"go1.9", 20, false},
+		{"go2.0", 20, true},
+		{"devel go1.22-abcdef", 20, true},
+	}
+	for _, c := range cases {
+		if got := goVersionAtLeast(c.v, c.minor); got != c.want {
+			t.Errorf("goVersionAtLeast(%q, %d) = %v, want %v", c.v, c.minor, got, c.want)
+		}
+	}
+}
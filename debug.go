@@ -0,0 +1,315 @@
+package maintest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Debugger is a small client for the Debug Adapter Protocol server started by DebugDAP, letting
+// a test drive the paused child process programmatically (set a breakpoint, continue, inspect
+// locals) instead of attaching a human debugger.
+type Debugger struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	writeMu sync.Mutex // serializes writes to conn across concurrent request() calls
+	seq     int32
+
+	mu      sync.Mutex
+	pending map[int]chan dapMessage // requestSeq -> waiter, filled in by readLoop
+	readErr error                   // set by readLoop once the connection/read loop has ended
+
+	events chan dapMessage // DAP events observed by readLoop while nothing is waiting on them
+
+	bpMu        sync.Mutex
+	breakpoints map[string][]int // file -> lines set so far; setBreakpoints replaces the whole set per file, so SetBreakpoint must resend it in full
+}
+
+// Debugger connects to the dlv DAP server started for the most recent DebugDAP invocation and
+// completes the initialize/attach handshake. It must be called after Command or NewInvocation
+// has started the child process (dlv exec already launches and pauses it), and only when the
+// Exe was built with DebugDAP.
+func (b *Exe) Debugger() (*Debugger, error) {
+	if !b.dapMode {
+		return nil, fmt.Errorf("maintest: Debugger requires the DebugDAP option")
+	}
+
+	addr := "localhost:" + strconv.Itoa(b.dapPort)
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ { // dlv takes a moment to start listening after the process is spawned
+		conn, err = net.Dial("tcp", addr)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to dlv dap at %s: %w", addr, err)
+	}
+
+	d := &Debugger{
+		conn:        conn,
+		br:          bufio.NewReader(conn),
+		pending:     make(map[int]chan dapMessage),
+		events:      make(chan dapMessage, 16),
+		breakpoints: make(map[string][]int),
+	}
+	go d.readLoop()
+	if err := d.handshake(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// handshake runs the DAP sequence dlv expects before a session can set breakpoints and resume
+// execution: initialize, wait for the adapter's "initialized" event, attach to the process dlv
+// already exec'd and paused, then signal configurationDone.
+//
+// dlv exec has already launched the target (that's what "exec" means), so the client attaches
+// to it rather than asking the adapter to launch it itself.
+func (d *Debugger) handshake() error {
+	if _, err := d.request("initialize", map[string]any{"clientID": "maintest", "adapterID": "dlv"}); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := d.waitForEvent("initialized", 5*time.Second); err != nil {
+		return err
+	}
+	if _, err := d.request("attach", map[string]any{"request": "attach", "mode": "local"}); err != nil {
+		return fmt.Errorf("attach: %w", err)
+	}
+	if _, err := d.request("configurationDone", map[string]any{}); err != nil {
+		return fmt.Errorf("configurationDone: %w", err)
+	}
+	return nil
+}
+
+// SetBreakpoint sets a breakpoint at file:line in the debugged process. DAP's setBreakpoints
+// request replaces the whole breakpoint set for a source file on every call, so SetBreakpoint
+// tracks every line requested so far for file and resends the full list, rather than just the
+// newly added line (which would silently clear any earlier breakpoints in the same file).
+func (d *Debugger) SetBreakpoint(file string, line int) error {
+	d.bpMu.Lock()
+	d.breakpoints[file] = append(d.breakpoints[file], line)
+	lines := append([]int(nil), d.breakpoints[file]...)
+	d.bpMu.Unlock()
+
+	bps := make([]map[string]any, len(lines))
+	for i, l := range lines {
+		bps[i] = map[string]any{"line": l}
+	}
+	_, err := d.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": file},
+		"breakpoints": bps,
+	})
+	return err
+}
+
+// Continue resumes the debugged process until the next breakpoint, or exit.
+func (d *Debugger) Continue() error {
+	_, err := d.request("continue", map[string]any{"threadId": 1})
+	return err
+}
+
+// EvalExpression evaluates expr in the context of the current, paused stack frame and returns
+// its printed result.
+func (d *Debugger) EvalExpression(expr string) (string, error) {
+	resp, err := d.request("evaluate", map[string]any{"expression": expr, "context": "repl"})
+	if err != nil {
+		return "", err
+	}
+	var body struct {
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(resp, &body); err != nil {
+		return "", fmt.Errorf("evaluate: %w", err)
+	}
+	return body.Result, nil
+}
+
+// Stack returns the names of the functions on the current call stack, innermost first.
+func (d *Debugger) Stack() ([]string, error) {
+	resp, err := d.request("stackTrace", map[string]any{"threadId": 1})
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		StackFrames []struct {
+			Name string `json:"name"`
+		} `json:"stackFrames"`
+	}
+	if err := json.Unmarshal(resp, &body); err != nil {
+		return nil, fmt.Errorf("stackTrace: %w", err)
+	}
+	names := make([]string, len(body.StackFrames))
+	for i, f := range body.StackFrames {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// Close ends the DAP session and closes the underlying connection.
+func (d *Debugger) Close() error {
+	return d.conn.Close()
+}
+
+// dapMessage is the subset of the DAP JSON message schema maintest needs for requests,
+// responses, and the events it must watch for (e.g. "initialized") while waiting on a response.
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    bool            `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// request sends a DAP request and blocks for its matching response, which readLoop delivers on a
+// per-request channel keyed by seq.
+func (d *Debugger) request(command string, args map[string]any) (json.RawMessage, error) {
+	seq := int(atomic.AddInt32(&d.seq, 1))
+	argBytes, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan dapMessage, 1)
+	d.mu.Lock()
+	if d.readErr != nil {
+		err := d.readErr
+		d.mu.Unlock()
+		return nil, fmt.Errorf("dap %s: %w", command, err)
+	}
+	d.pending[seq] = ch
+	d.mu.Unlock()
+
+	d.writeMu.Lock()
+	err = writeDAPMessage(d.conn, dapMessage{Seq: seq, Type: "request", Command: command, Arguments: argBytes})
+	d.writeMu.Unlock()
+	if err != nil {
+		d.mu.Lock()
+		delete(d.pending, seq)
+		d.mu.Unlock()
+		return nil, err
+	}
+
+	msg, ok := <-ch
+	if !ok {
+		d.mu.Lock()
+		err := d.readErr
+		d.mu.Unlock()
+		return nil, fmt.Errorf("dap %s: connection closed: %w", command, err)
+	}
+	if !msg.Success {
+		return nil, fmt.Errorf("dap %s: %s", command, msg.Message)
+	}
+	return msg.Body, nil
+}
+
+// readLoop continuously drains DAP messages off the connection for the life of the session,
+// dispatching responses to the request() call waiting on their seq and events to d.events. Running
+// this in the background, rather than only reading inside request(), is what lets an event like
+// "initialized" be observed even though it arrives as its own message after the response to the
+// request that triggered it, with nothing else reading the socket at that moment.
+func (d *Debugger) readLoop() {
+	for {
+		msg, err := readDAPMessage(d.br)
+		if err != nil {
+			d.mu.Lock()
+			d.readErr = err
+			pending := d.pending
+			d.pending = nil
+			d.mu.Unlock()
+			for _, ch := range pending {
+				close(ch)
+			}
+			return
+		}
+		if msg.Type == "event" {
+			select {
+			case d.events <- *msg:
+			default: // events channel full; drop rather than block the read loop
+			}
+			continue
+		}
+		if msg.Type == "response" {
+			d.mu.Lock()
+			ch, ok := d.pending[msg.RequestSeq]
+			if ok {
+				delete(d.pending, msg.RequestSeq)
+			}
+			d.mu.Unlock()
+			if ok {
+				ch <- *msg
+			}
+		}
+	}
+}
+
+// waitForEvent blocks until readLoop delivers a DAP event named name or timeout elapses. Events
+// with other names taken off d.events while waiting are discarded.
+func (d *Debugger) waitForEvent(name string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg := <-d.events:
+			if msg.Event == name {
+				return nil
+			}
+		case <-deadline:
+			return fmt.Errorf("dap: timed out waiting for %q event", name)
+		}
+	}
+}
+
+// writeDAPMessage frames and writes msg to w using DAP's Content-Length-prefixed encoding.
+func writeDAPMessage(w io.Writer, msg dapMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// readDAPMessage reads and parses the next Content-Length-framed DAP message from r.
+func readDAPMessage(r *bufio.Reader) (*dapMessage, error) {
+	var length int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("dap: bad Content-Length %q: %w", value, err)
+			}
+		}
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	var msg dapMessage
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,154 @@
+package maintest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeDAPServer is a minimal stand-in for dlv's DAP server: it replies "success" to every
+// request it's told to expect, and can emit events on demand, so Debugger's framing and
+// handshake logic can be exercised without a real dlv binary.
+type fakeDAPServer struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func (s *fakeDAPServer) respondTo(command string, body any) error {
+	msg, err := readDAPMessage(s.br)
+	if err != nil {
+		return err
+	}
+	if msg.Command != command {
+		return fmt.Errorf("fake dap server: got command %q, want %q", msg.Command, command)
+	}
+	var bodyBytes json.RawMessage
+	if body != nil {
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	return writeDAPMessage(s.conn, dapMessage{Type: "response", RequestSeq: msg.Seq, Success: true, Command: command, Body: bodyBytes})
+}
+
+func (s *fakeDAPServer) sendEvent(name string) error {
+	return writeDAPMessage(s.conn, dapMessage{Type: "event", Event: name})
+}
+
+// newTestDebugger builds a Debugger around conn with its readLoop already running, the same way
+// Debugger() does, without performing the handshake.
+func newTestDebugger(conn net.Conn) *Debugger {
+	d := &Debugger{
+		conn:        conn,
+		br:          bufio.NewReader(conn),
+		pending:     make(map[int]chan dapMessage),
+		events:      make(chan dapMessage, 16),
+		breakpoints: make(map[string][]int),
+	}
+	go d.readLoop()
+	return d
+}
+
+func TestDebuggerHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	d := newTestDebugger(client)
+	srv := &fakeDAPServer{conn: server, br: bufio.NewReader(server)}
+
+	done := make(chan error, 1)
+	go func() {
+		if err := srv.respondTo("initialize", nil); err != nil {
+			done <- err
+			return
+		}
+		if err := srv.sendEvent("initialized"); err != nil {
+			done <- err
+			return
+		}
+		if err := srv.respondTo("attach", nil); err != nil {
+			done <- err
+			return
+		}
+		done <- srv.respondTo("configurationDone", nil)
+	}()
+
+	if err := d.handshake(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("fake server: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fake server did not finish")
+	}
+}
+
+func TestDebuggerSetBreakpointResendsFullSet(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	d := newTestDebugger(client)
+
+	var gotLines [][]int
+	done := make(chan error, 1)
+	go func() {
+		serverReader := bufio.NewReader(server)
+		for i := 0; i < 2; i++ {
+			msg, err := readDAPMessage(serverReader)
+			if err != nil {
+				done <- err
+				return
+			}
+			var args struct {
+				Breakpoints []struct {
+					Line int `json:"line"`
+				} `json:"breakpoints"`
+			}
+			if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+				done <- err
+				return
+			}
+			var lines []int
+			for _, bp := range args.Breakpoints {
+				lines = append(lines, bp.Line)
+			}
+			gotLines = append(gotLines, lines)
+			if err := writeDAPMessage(server, dapMessage{Type: "response", RequestSeq: msg.Seq, Success: true, Command: "setBreakpoints"}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	if err := d.SetBreakpoint("main.go", 10); err != nil {
+		t.Fatalf("SetBreakpoint: %v", err)
+	}
+	if err := d.SetBreakpoint("main.go", 20); err != nil {
+		t.Fatalf("SetBreakpoint: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("fake server: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fake server did not finish")
+	}
+
+	if len(gotLines) != 2 || len(gotLines[0]) != 1 || len(gotLines[1]) != 2 {
+		t.Fatalf("got %v, want second setBreakpoints call to resend both lines", gotLines)
+	}
+	if gotLines[1][0] != 10 || gotLines[1][1] != 20 {
+		t.Fatalf("got %v, want [10 20]", gotLines[1])
+	}
+}
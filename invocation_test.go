@@ -0,0 +1,118 @@
+package maintest
+
+import (
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+func TestNewInvocationGivesEachRunItsOwnCoverageDir(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	exe, err := Build("add", Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := exe.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	const n = 3
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		inv := exe.NewInvocation("1", "3")
+		if _, err := inv.Output(); err != nil {
+			t.Fatalf("invocation %d: %v", i, err)
+		}
+		if exe.nativeMerge {
+			// Native merging shares the parent's GOCOVERDIR across invocations (see below).
+			if inv.CoverageDir != exe.CoverageDir {
+				t.Fatalf("invocation %d used coverage dir %s, want shared %s", i, inv.CoverageDir, exe.CoverageDir)
+			}
+			continue
+		}
+		if seen[inv.CoverageDir] {
+			t.Fatalf("invocation %d reused coverage dir %s", i, inv.CoverageDir)
+		}
+		seen[inv.CoverageDir] = true
+	}
+
+	if exe.nativeMerge {
+		// Under native merging every invocation writes straight into the shared GOCOVERDIR
+		// (parallel-safe via the Go runtime's own unique file naming per process), so there are
+		// no per-invocation subdirs to check here; that scheme only applies to the non-native path.
+		return
+	}
+	dirs, err := covSubdirs(exe.CoverageDir)
+	if err != nil {
+		t.Fatalf("covSubdirs: %v", err)
+	}
+	if len(dirs) != n {
+		t.Fatalf("got %d invocation coverage dirs, want %d", len(dirs), n)
+	}
+}
+
+// TestNewInvocationConcurrentGivesEachRunItsOwnCoverageDir fires NewInvocation from many
+// goroutines at once, the scenario the atomic invocation counter exists for (tests calling
+// t.Parallel()), and checks (under -race) that no two concurrent invocations ever collide on a
+// coverage dir.
+func TestNewInvocationConcurrentGivesEachRunItsOwnCoverageDir(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	exe, err := Build("add", Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := exe.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	const n = 8
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inv := exe.NewInvocation("1", "3")
+			if _, err := inv.Output(); err != nil {
+				t.Errorf("invocation %d: %v", i, err)
+				return
+			}
+			if exe.nativeMerge {
+				if inv.CoverageDir != exe.CoverageDir {
+					t.Errorf("invocation %d used coverage dir %s, want shared %s", i, inv.CoverageDir, exe.CoverageDir)
+				}
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[inv.CoverageDir] {
+				t.Errorf("invocation %d reused coverage dir %s", i, inv.CoverageDir)
+			}
+			seen[inv.CoverageDir] = true
+		}()
+	}
+	wg.Wait()
+
+	if exe.nativeMerge {
+		return
+	}
+	dirs, err := covSubdirs(exe.CoverageDir)
+	if err != nil {
+		t.Fatalf("covSubdirs: %v", err)
+	}
+	if len(dirs) != n {
+		t.Fatalf("got %d invocation coverage dirs, want %d", len(dirs), n)
+	}
+}
@@ -10,12 +10,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 var (
@@ -36,7 +38,26 @@ type Exe struct {
 	extraArgs      []string // extra build args to pass when the executable is being built by go build
 	overrideCovDir string   // override of where to place the final merged coverage after executing all the tests (overrides -coverprofile flag)
 	delveOpts      []string // if non-empty, will execute exes with dlv exec [delveOpts] [exe...] to allow interactive debugging
+	dapMode        bool     // set by DebugDAP: run dlv in DAP mode instead of the plain headless API, so Debugger can attach programmatically
+	dapPort        int      // free port chosen for the most recent DebugDAP invocation's dlv DAP server
 
+	invCount    int32 // atomic counter giving each Command() call its own coverage subdir, safe for concurrent/parallel tests
+	nativeMerge bool  // true when CoverageDir is the parent `go test`'s own -test.gocoverdir (go1.20+), so Finish has nothing left to copy and merges only for WriteCoverage or reports
+
+	htmlReportPath   string  // if set by HTMLReport, Finish writes `go tool cover -html` here
+	funcReportPath   string  // if set by FuncReport, Finish writes `go tool cover -func` here
+	percentThreshold float64 // if thresholdSet, Finish fails when merged coverage is below this percentage
+	thresholdSet     bool
+}
+
+// Invocation pairs a *exec.Cmd with the coverage directory its GOCOVERDIR
+// points at, so a test can inspect or archive the coverage from just that one
+// run rather than only the total merged by Finish. Under native merging (see
+// chooseCoverageDir) every invocation shares Exe.CoverageDir instead of getting
+// a unique subdirectory.
+type Invocation struct {
+	*exec.Cmd
+	CoverageDir string // this invocation's GOCOVERDIR: a unique subdir of Exe.CoverageDir, or Exe.CoverageDir itself under native merging
 }
 
 type Option func(e *Exe)
@@ -70,6 +91,20 @@ func Debug(dlvArgs ...string) Option {
 	}
 }
 
+// DebugDAP is like Debug, but starts dlv headless in DAP mode
+// (https://microsoft.github.io/debug-adapter-protocol/) on an automatically chosen free port
+// instead of the plain interactive API Debug uses. Once the child process is running, call
+// Exe.Debugger to get a client that drives it programmatically (set breakpoints, continue,
+// evaluate expressions, inspect the stack) instead of attaching a human at a dlv prompt.
+// dlvArgs are passed through the same way as Debug.
+func DebugDAP(dlvArgs ...string) Option {
+	return func(e *Exe) {
+		e.extraArgs = append(e.extraArgs, `-gcflags=all=-N -l`)
+		e.dapMode = true
+		e.delveOpts = append(e.delveOpts, append([]string{"--headless"}, dlvArgs...)...)
+	}
+}
+
 // WriteCoverage redirects the coverage from -coverprofile to override path.
 func WriteCoverage(path string) Option {
 	return func(e *Exe) {
@@ -77,6 +112,27 @@ func WriteCoverage(path string) Option {
 	}
 }
 
+// HTMLReport causes Finish to additionally write an HTML coverage report (as produced by
+// `go tool cover -html`) for the merged profile to path.
+func HTMLReport(path string) Option {
+	return func(e *Exe) { e.htmlReportPath = path }
+}
+
+// FuncReport causes Finish to additionally write a per-function coverage summary (as produced
+// by `go tool cover -func`) for the merged profile to path.
+func FuncReport(path string) Option {
+	return func(e *Exe) { e.funcReportPath = path }
+}
+
+// PercentThreshold causes Finish to fail with an error if the merged coverage percentage of the
+// built executable, parsed from `go tool cover -func`'s total line, falls below pct (0-100).
+func PercentThreshold(pct float64) Option {
+	return func(e *Exe) {
+		e.percentThreshold = pct
+		e.thresholdSet = true
+	}
+}
+
 // Package will cause `go build` to run on a different package than
 // the current directory. The package must have an executable (`package main`).
 func Package(pkg string) Option {
@@ -102,10 +158,11 @@ func Build(exeName string, opts ...Option) (*Exe, error) {
 		return nil, err
 	}
 
-	exe.CoverageDir = filepath.Join(exe.binDir, ".coverage")
-	err = os.MkdirAll(exe.CoverageDir, 0700)
-	if err != nil {
-		return nil, err
+	exe.CoverageDir, exe.nativeMerge = chooseCoverageDir(exe.binDir, findGoCoverDir(), goCoverMergeSupported())
+	if !exe.nativeMerge {
+		if err := os.MkdirAll(exe.CoverageDir, 0700); err != nil {
+			return nil, err
+		}
 	}
 
 	DebugLog.Printf("GOCOVERDIR: %s", exe.CoverageDir)
@@ -134,14 +191,62 @@ func Build(exeName string, opts ...Option) (*Exe, error) {
 }
 
 // Command returns Cmd ready to be run that will invoke the built test executable and output the results
-// to the shared coverage directory.
+// to its own subdirectory of the shared coverage directory (or, under native merging, straight into
+// the shared directory, where the Go runtime names each process's files uniquely), making it safe to
+// call Command from multiple t.Parallel() tests at once. Use NewInvocation instead if the test needs
+// to inspect the coverage produced by just this one run.
 func (b *Exe) Command(args ...string) *exec.Cmd {
+	return b.NewInvocation(args...).Cmd
+}
+
+// NewInvocation is like Command, but also returns the coverage subdirectory allocated for this run so
+// a test can inspect or archive it after the command finishes, rather than only the total Finish merges.
+// Under native merging no subdirectory is allocated and the returned CoverageDir is Exe.CoverageDir.
+func (b *Exe) NewInvocation(args ...string) *Invocation {
+	return b.commandAt(b.Path, args...)
+}
+
+// commandAt is the shared implementation behind NewInvocation and Suite.Command: it builds an
+// Invocation that runs the executable at path (b.Path for a plain Exe, or a same-binary symlink
+// published under another name for a Suite), wired up with its own coverage subdir and, if
+// configured, dlv.
+func (b *Exe) commandAt(path string, args ...string) *Invocation {
+	covDir := b.CoverageDir
+	if !b.nativeMerge {
+		// Under nativeMerge, CoverageDir is the parent `go test`'s own -test.gocoverdir, and
+		// the Go runtime already names each process's covmeta/covcounters files uniquely
+		// (by build id, pid, and timestamp), so writing straight into it is already
+		// parallel-safe. Without nativeMerge, CoverageDir is maintest's own flat temp dir,
+		// so give this invocation a subdir to avoid collisions between parallel runs.
+		n := atomic.AddInt32(&b.invCount, 1)
+		covDir = filepath.Join(b.CoverageDir, fmt.Sprintf("inv-%d-%d", n, os.Getpid()))
+		if err := os.MkdirAll(covDir, 0700); err != nil {
+			// Command/NewInvocation have no error return today; fall back to the shared
+			// top-level dir so coverage isn't silently dropped, same as before this existed.
+			DebugLog.Printf("mkdir invocation coverage dir %s: %v, falling back to %s", covDir, err, b.CoverageDir)
+			covDir = b.CoverageDir
+		}
+	}
+
 	var cmd *exec.Cmd
 	if len(b.delveOpts) > 0 {
 		dlvArgs := []string{"exec"}
-		dlvArgs = append(dlvArgs, "--log-dest", "/dev/null")
+		if b.dapMode {
+			// dlv negotiates DAP on the same --headless socket as its plain JSON-RPC API; there
+			// is no separate flag to request it. We only need to remember the port so Debugger
+			// can dial it later.
+			port, err := freePort()
+			if err != nil {
+				DebugLog.Printf("dap: choosing port: %v, falling back to dlv's default", err)
+			} else {
+				b.dapPort = port
+			}
+			dlvArgs = append(dlvArgs, "-l", "localhost:"+strconv.Itoa(b.dapPort))
+		} else {
+			dlvArgs = append(dlvArgs, "--log-dest", "/dev/null")
+		}
 		dlvArgs = append(dlvArgs, b.delveOpts...)
-		dlvArgs = append(dlvArgs, b.Path, "--")
+		dlvArgs = append(dlvArgs, path, "--")
 		dlvArgs = append(dlvArgs, args...)
 		dlvExe := "dlv"
 		if runtime.GOOS == "windows" {
@@ -149,33 +254,58 @@ func (b *Exe) Command(args ...string) *exec.Cmd {
 		}
 		cmd = exec.Command(dlvExe, dlvArgs...)
 	} else {
-		cmd = exec.Command(b.Path, args...)
+		cmd = exec.Command(path, args...)
 	}
 
-	cmd.Env = append(os.Environ(), "GOCOVERDIR="+b.CoverageDir)
-	return cmd
+	cmd.Env = append(os.Environ(), "GOCOVERDIR="+covDir)
+	return &Invocation{Cmd: cmd, CoverageDir: covDir}
 }
 
 // Finish will merge all the coverage from the previous executions and write the output to -coverprofile.
 // This func uses the `go tool covdata` command with textfmt for backwards compatibility with existing
 // tools, e.g. `go tool covdata textfmt -i b.CoverageDir -o '-coverprofile'`. -coverprofile is parsed from os.Args.
+//
+// On go1.20+, when Build ran under `go test -cover` and found -test.gocoverdir already set,
+// counters were written directly into it (see nativeMerge in Build), so `go test` itself already
+// merged and reports this executable's coverage alongside the test binary's own; Finish has
+// nothing left to do unless WriteCoverage overrode where the merged profile should go, or
+// HTMLReport, FuncReport or PercentThreshold asked for reports built from a merged profile.
 func (b *Exe) Finish() error {
 	defer os.RemoveAll(b.binDir)
 	DebugLog.Printf("go test args: %s", strings.Join(os.Args, " "))
 
+	wantReports := b.htmlReportPath != "" || b.funcReportPath != "" || b.thresholdSet
+	if b.nativeMerge && b.overrideCovDir == "" && !wantReports {
+		return nil
+	}
+
 	coverprofile := findArg("test.coverprofile")
 	if b.overrideCovDir != "" {
 		coverprofile = b.overrideCovDir
 	}
-	if coverprofile != "" { // merge the output of the executable to the coverprofile dir as well
-		err := mergeGoCover(b.CoverageDir, coverprofile)
+	if coverprofile == "" && wantReports {
+		// The reports below need a text profile to read, but -test.coverprofile/WriteCoverage
+		// weren't set; fall back to a throwaway one inside binDir (removed with everything else on return).
+		coverprofile = filepath.Join(b.binDir, "cover.out")
+	}
+	if coverprofile != "" { // merge the output of every invocation's subdir to the coverprofile dir as well
+		dirs, err := covSubdirs(b.CoverageDir)
 		if err != nil {
 			return err
 		}
+		if err := mergeGoCover(dirs, coverprofile); err != nil {
+			return err
+		}
+	}
+
+	if wantReports {
+		if err := b.writeReports(coverprofile); err != nil {
+			return err
+		}
 	}
 
 	gocoverdir := findGoCoverDir()
-	if gocoverdir != "" {
+	if gocoverdir != "" && gocoverdir != b.CoverageDir {
 		// Copy all the coverage files to the configured directory
 		DebugLog.Printf("copying coverage from %s to %s", b.CoverageDir, gocoverdir)
 		err := os.MkdirAll(gocoverdir, 0766)
@@ -194,13 +324,33 @@ func (b *Exe) Finish() error {
 	return nil
 }
 
-// mergeGoCover takes the new binary coverage files and merges them all to a dst file
-func mergeGoCover(from, dst string) error {
+// covSubdirs returns every per-invocation coverage subdirectory under root (see NewInvocation). If
+// none exist (e.g. no commands were run, or Finish is merging a dir from before per-invocation
+// subdirs existed), root itself is returned so a plain GOCOVERDIR still merges correctly.
+func covSubdirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(root, entry.Name()))
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = []string{root}
+	}
+	return dirs, nil
+}
+
+// mergeGoCover takes the new binary coverage files from every dir in from and merges them all to a dst file
+func mergeGoCover(from []string, dst string) error {
 	gotool, err := goTool()
 	if err != nil {
 		return err
 	}
-	covdata := exec.Command(gotool, "tool", "covdata", "textfmt", "-i", from, "-o", dst)
+	covdata := exec.Command(gotool, "tool", "covdata", "textfmt", "-i", strings.Join(from, ","), "-o", dst)
 	DebugLog.Printf("%s", strings.Join(covdata.Args, " "))
 	out, err := covdata.CombinedOutput()
 	if err != nil {
@@ -217,6 +367,47 @@ func findGoCoverDir() string {
 	return d
 }
 
+// chooseCoverageDir decides where an Exe's CoverageDir should live: the parent `go test`'s own
+// -test.gocoverdir when go1.20+ native merging is available and one was provided (native=true,
+// nothing more for Build to create), or a fresh ".coverage" subdirectory of binDir that Finish
+// will merge into -coverprofile manually otherwise (native=false, caller must create it).
+func chooseCoverageDir(binDir, gocoverdir string, nativeSupported bool) (dir string, native bool) {
+	if gocoverdir != "" && nativeSupported {
+		// go1.20+ already merges profiles from every GOCOVERDIR-writing process into the
+		// `go test` run's own coverage set, so write straight there instead of a throwaway
+		// temp dir that Finish would otherwise have to fold in by shelling out to
+		// `go tool covdata textfmt`.
+		return gocoverdir, true
+	}
+	return filepath.Join(binDir, ".coverage"), false
+}
+
+// goCoverMergeSupported reports whether the running Go toolchain merges coverage profiles from
+// multiple GOCOVERDIR-writing processes natively (go1.20+), the way `go test -cover` does for its
+// own binary plus any other instrumented executable pointed at the same -test.gocoverdir.
+func goCoverMergeSupported() bool {
+	return goVersionAtLeast(runtime.Version(), 20)
+}
+
+// goVersionAtLeast reports whether v (as returned by runtime.Version(), e.g. "go1.21.3") is Go 1.minor
+// or newer. Non-release versions (e.g. "devel ...") are treated as newer than any release.
+func goVersionAtLeast(v string, minor int) bool {
+	v = strings.TrimPrefix(v, "go")
+	major, rest, ok := strings.Cut(v, ".")
+	if !ok {
+		return !strings.HasPrefix(v, "1") // "devel ..." and friends
+	}
+	if major != "1" {
+		return true // go2+
+	}
+	rest, _, _ = strings.Cut(rest, ".")
+	m, err := strconv.Atoi(rest)
+	if err != nil {
+		return false
+	}
+	return m >= minor
+}
+
 func findArg(key string) string {
 	for _, arg := range os.Args {
 		if strings.HasPrefix(arg, "-"+key) {
@@ -230,20 +421,23 @@ func findArg(key string) string {
 	return ""
 }
 
-// copyAll copies all the coverage files from src to dst folders
+// copyAll copies all the coverage files from src to dst, flattening any per-invocation subdirs
+// (see NewInvocation) since coverage filenames within them are already unique per run.
 func copyAll(src, dst string) error {
 	entries, err := os.ReadDir(src)
 	if err != nil {
 		return err
 	}
 	for _, entry := range entries {
-		DebugLog.Printf("found %s", entry.Name())
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-
 		if entry.IsDir() {
+			if err := copyAll(filepath.Join(src, entry.Name()), dst); err != nil {
+				return err
+			}
 			continue
 		}
+		DebugLog.Printf("found %s", entry.Name())
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
 		if err := copyFile(srcPath, dstPath); err != nil {
 			return err
 		}
@@ -268,6 +462,17 @@ func copyFile(src, dst string) error {
 	return err
 }
 
+// freePort asks the OS for a free TCP port by briefly binding to port 0 and reporting what it
+// chose, so a dlv DAP server can be started without a fixed, possibly-already-used port.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
 // GoTool reports the path to the Go tool.
 func goTool() (string, error) {
 	var exeSuffix string
@@ -0,0 +1,86 @@
+package maintest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// writeReports renders the requested HTMLReport/FuncReport/PercentThreshold outputs from the
+// already-merged text profile at coverprofile.
+func (b *Exe) writeReports(coverprofile string) error {
+	if b.htmlReportPath != "" {
+		if err := runGoToolCover("-html="+coverprofile, "-o", b.htmlReportPath); err != nil {
+			return fmt.Errorf("html report: %w", err)
+		}
+	}
+
+	if b.funcReportPath != "" || b.thresholdSet {
+		out, err := goToolCoverFunc(coverprofile)
+		if err != nil {
+			return err
+		}
+		if b.funcReportPath != "" {
+			if err := os.WriteFile(b.funcReportPath, out, 0644); err != nil {
+				return fmt.Errorf("func report: %w", err)
+			}
+		}
+		if b.thresholdSet {
+			got, err := parseTotalPercent(out)
+			if err != nil {
+				return fmt.Errorf("percent threshold: %w", err)
+			}
+			if got < b.percentThreshold {
+				return fmt.Errorf("coverage %.1f%% is below threshold %.1f%%", got, b.percentThreshold)
+			}
+		}
+	}
+	return nil
+}
+
+// runGoToolCover runs `go tool cover args...`.
+func runGoToolCover(args ...string) error {
+	gotool, err := goTool()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(gotool, append([]string{"tool", "cover"}, args...)...)
+	DebugLog.Printf("%s", strings.Join(cmd.Args, " "))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go tool cover: %s", string(out))
+	}
+	return nil
+}
+
+// goToolCoverFunc runs `go tool cover -func=coverprofile` and returns its output.
+func goToolCoverFunc(coverprofile string) ([]byte, error) {
+	gotool, err := goTool()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(gotool, "tool", "cover", "-func="+coverprofile)
+	DebugLog.Printf("%s", strings.Join(cmd.Args, " "))
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go tool cover -func: %s", string(out))
+	}
+	return out, nil
+}
+
+// parseTotalPercent extracts the percentage from `go tool cover -func`'s final "total:" line,
+// e.g. "total:\t\t\t\t\t(statements)\t87.5%".
+func parseTotalPercent(funcOutput []byte) (float64, error) {
+	lines := strings.Split(strings.TrimRight(string(funcOutput), "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 0 || fields[0] != "total:" {
+			continue
+		}
+		pctField := fields[len(fields)-1]
+		return strconv.ParseFloat(strings.TrimSuffix(pctField, "%"), 64)
+	}
+	return 0, fmt.Errorf("no total line found in:\n%s", funcOutput)
+}
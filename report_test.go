@@ -0,0 +1,77 @@
+package maintest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTotalPercent(t *testing.T) {
+	out := "github.com/masp/maintest/example/add.go:3:\tAdd\t\t100.0%\n" +
+		"total:\t\t\t\t\t(statements)\t87.5%\n"
+	got, err := parseTotalPercent([]byte(out))
+	if err != nil {
+		t.Fatalf("parseTotalPercent: %v", err)
+	}
+	if got != 87.5 {
+		t.Errorf("got %v, want 87.5", got)
+	}
+}
+
+func TestParseTotalPercentMissing(t *testing.T) {
+	_, err := parseTotalPercent([]byte("github.com/masp/maintest/example/add.go:3:\tAdd\t\t100.0%\n"))
+	if err == nil {
+		t.Fatal("expected error for output with no total line")
+	}
+}
+
+// TestWriteReportsEndToEnd builds a real instrumented binary, runs it, merges coverage, and
+// checks writeReports actually produces the HTML/func reports and enforces the threshold - the
+// behavior this request added, never previously exercised by a test.
+func TestWriteReportsEndToEnd(t *testing.T) {
+	if _, err := goTool(); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "cover.html")
+	funcPath := filepath.Join(dir, "cover.func.txt")
+
+	exe, err := Build("add", Package("github.com/masp/maintest/example"),
+		HTMLReport(htmlPath), FuncReport(funcPath), PercentThreshold(0))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := exe.NewInvocation("1", "3").Output(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if err := exe.Finish(); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+
+	if _, err := os.Stat(htmlPath); err != nil {
+		t.Errorf("html report: %v", err)
+	}
+	if _, err := os.Stat(funcPath); err != nil {
+		t.Errorf("func report: %v", err)
+	}
+}
+
+// TestWriteReportsThresholdFailure checks that Finish reports an error when merged coverage
+// falls below an unreachable PercentThreshold.
+func TestWriteReportsThresholdFailure(t *testing.T) {
+	if _, err := goTool(); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	exe, err := Build("add", Package("github.com/masp/maintest/example"), PercentThreshold(101))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := exe.NewInvocation("1", "3").Output(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if err := exe.Finish(); err == nil {
+		t.Fatal("expected Finish to fail an unreachable threshold")
+	}
+}
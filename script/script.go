@@ -0,0 +1,281 @@
+// Package script provides a small testscript-style DSL for driving a maintest.Exe (or a single
+// named command of a maintest.Suite, via SuiteCommand) through declarative .txt script files
+// instead of hand-rolled exec.Cmd boilerplate, while still sharing the executable's
+// coverage-merging story.
+//
+// A script is run line by line; blank lines and lines starting with # are skipped. Arguments are
+// split on spaces and tabs, and single or double quotes group words. The commands are:
+//
+//	exec args...     run the executor with args; a "!" prefix expects it to fail
+//	stdout regexp    match the last exec's stdout; a "!" prefix expects no match
+//	stderr regexp    same, for the last exec's stderr
+//	cmp a b          compare two files for equality; "stdout"/"stderr" name the last exec's output
+//	env KEY=VALUE... set environment variables for every later exec
+//	cd dir           change the directory that exec runs in and cmp reads files from
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/masp/maintest"
+)
+
+// Executor is the minimum surface script needs to run commands. It is satisfied by
+// *maintest.Exe directly, and by SuiteCommand for a single named command of a maintest.Suite.
+type Executor interface {
+	Command(args ...string) *exec.Cmd
+}
+
+// SuiteCommand adapts one named command of a maintest.Suite to the Executor interface, so a
+// script can drive it the same way it would a plain maintest.Exe.
+type SuiteCommand struct {
+	Suite *maintest.Suite
+	Name  string
+}
+
+// Command runs Name through Suite, satisfying Executor.
+func (c SuiteCommand) Command(args ...string) *exec.Cmd {
+	return c.Suite.Command(c.Name, args...)
+}
+
+var _ Executor = SuiteCommand{}
+
+// Run parses every file matching glob as a script (see the package doc for the command
+// syntax) and runs each as its own subtest of t, driving exe. glob selects script files only —
+// every matched file is parsed and executed as a script, so a cmp fixture living alongside your
+// scripts must use an extension glob won't match (e.g. ".want"), not ".txt".
+func Run(t *testing.T, glob string, exe Executor) {
+	t.Helper()
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		t.Fatalf("script: bad glob %q: %v", glob, err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("script: no files matched %q", glob)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("script: %v", err)
+			}
+			runScript(t, exe, file, string(src))
+		})
+	}
+}
+
+// state carries what the commands in a single script file operate on as execution proceeds.
+type state struct {
+	t      *testing.T
+	exe    Executor
+	dir    string            // cd'd working directory, used to resolve cmp file paths
+	env    map[string]string // env set by "env" lines, applied to every subsequent exec
+	stdout string            // captured output of the most recent exec
+	stderr string
+}
+
+// runScript executes every line of src in order against exe.
+func runScript(t *testing.T, exe Executor, file, src string) {
+	t.Helper()
+	s := &state{t: t, exe: exe, dir: filepath.Dir(file), env: map[string]string{}}
+	for lineNo, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := s.runLine(line); err != nil {
+			t.Fatalf("%s:%d: %s: %v", file, lineNo+1, line, err)
+		}
+	}
+}
+
+// runLine executes a single command line, e.g. `exec add 1 3`, `stdout '^4$'`, `! exec add a b`.
+func (s *state) runLine(line string) error {
+	s.t.Helper()
+	negate := false
+	if strings.HasPrefix(line, "!") {
+		negate = true
+		line = strings.TrimSpace(line[1:])
+	}
+
+	args, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	cmd, rest := args[0], args[1:]
+
+	switch cmd {
+	case "exec":
+		return s.cmdExec(negate, rest)
+	case "stdout":
+		return s.cmdMatch(negate, "stdout", s.stdout, rest)
+	case "stderr":
+		return s.cmdMatch(negate, "stderr", s.stderr, rest)
+	case "cmp":
+		return s.cmdCmp(rest)
+	case "env":
+		return s.cmdEnv(rest)
+	case "cd":
+		return s.cmdCd(rest)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func (s *state) cmdExec(negate bool, args []string) error {
+	s.t.Helper()
+	if len(args) == 0 {
+		return fmt.Errorf("exec: missing program arguments")
+	}
+	cmd := s.exe.Command(args...)
+	cmd.Dir = s.dir
+	if len(s.env) > 0 {
+		env := append([]string{}, cmd.Env...)
+		for k, v := range s.env {
+			env = append(env, k+"="+v)
+		}
+		cmd.Env = env
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	s.stdout, s.stderr = stdout.String(), stderr.String()
+
+	if negate {
+		if err == nil {
+			return fmt.Errorf("exec %s: unexpectedly succeeded", strings.Join(args, " "))
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("exec %s: %w\nstdout:\n%s\nstderr:\n%s", strings.Join(args, " "), err, s.stdout, s.stderr)
+	}
+	return nil
+}
+
+func (s *state) cmdMatch(negate bool, name, got string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s: want exactly one regexp argument", name)
+	}
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	// Drop a single trailing newline before matching so patterns like '^4$' match one-line
+	// "4\n" output: regexp's $ anchors to the true end of text, not before a trailing newline,
+	// without the (?m) flag.
+	matched := re.MatchString(strings.TrimSuffix(got, "\n"))
+	if matched == negate {
+		return fmt.Errorf("%s: match of /%s/ = %v, want %v\ngot:\n%s", name, args[0], matched, !negate, got)
+	}
+	return nil
+}
+
+// cmdCmp compares two files for byte equality. The special names "stdout" and "stderr" refer to
+// the captured output of the most recent exec instead of a file on disk.
+func (s *state) cmdCmp(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("cmp: want exactly two arguments")
+	}
+	a, err := s.read(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := s.read(args[1])
+	if err != nil {
+		return err
+	}
+	if a != b {
+		return fmt.Errorf("cmp %s %s: mismatch\n--- %s\n%s\n--- %s\n%s", args[0], args[1], args[0], a, args[1], b)
+	}
+	return nil
+}
+
+func (s *state) read(name string) (string, error) {
+	switch name {
+	case "stdout":
+		return s.stdout, nil
+	case "stderr":
+		return s.stderr, nil
+	default:
+		b, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return "", fmt.Errorf("cmp: %w", err)
+		}
+		return string(b), nil
+	}
+}
+
+func (s *state) cmdEnv(args []string) error {
+	for _, kv := range args {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("env: %q is not in KEY=VALUE form", kv)
+		}
+		s.env[k] = v
+	}
+	return nil
+}
+
+func (s *state) cmdCd(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("cd: want exactly one directory argument")
+	}
+	dir := args[0]
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.dir, dir)
+	}
+	s.dir = dir
+	return nil
+}
+
+// splitArgs tokenizes a line into words, honoring single- and double-quoted arguments so that
+// e.g. `stdout '^4$'` produces []string{"stdout", "^4$"} rather than splitting on the space
+// inside the quotes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var buf strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				buf.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, buf.String())
+				buf.Reset()
+				inWord = false
+			}
+		default:
+			buf.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
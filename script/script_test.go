@@ -0,0 +1,89 @@
+package script
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/masp/maintest"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{"exec add 1 3", []string{"exec", "add", "1", "3"}},
+		{"stdout '^4$'", []string{"stdout", "^4$"}},
+		{`env FOO=bar "BAZ=a b"`, []string{"env", "FOO=bar", "BAZ=a b"}},
+		{"", nil},
+	}
+	for _, c := range cases {
+		got, err := splitArgs(c.line)
+		if err != nil {
+			t.Fatalf("splitArgs(%q): %v", c.line, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitArgs(%q) = %#v, want %#v", c.line, got, c.want)
+		}
+	}
+}
+
+// fixedProgram is an Executor that always runs the same system binary, used to exercise the
+// script engine itself (exec/stdout/cmp/! exec) without depending on the Go toolchain
+// being available to build a maintest.Exe.
+type fixedProgram string
+
+func (p fixedProgram) Command(args ...string) *exec.Cmd {
+	return exec.Command(string(p), args...)
+}
+
+func TestRunEcho(t *testing.T) {
+	Run(t, "testdata/echo/*.txt", fixedProgram("echo"))
+}
+
+func TestRunExecFailure(t *testing.T) {
+	Run(t, "testdata/fail/*.txt", fixedProgram("false"))
+}
+
+// TestRunWithExe exercises script.Run against a real maintest.Exe, the way it's meant to be
+// used, rather than the fixedProgram stand-in above. It's skipped where the Go toolchain
+// maintest.Build shells out to isn't available.
+func TestRunWithExe(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	exe, err := maintest.Build("add", maintest.Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := exe.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	Run(t, "testdata/add/*.txt", exe)
+}
+
+// TestRunWithSuiteCommand exercises script.Run against a maintest.Suite through SuiteCommand, the
+// wrapper this package introduced to make a Suite's named commands satisfy Executor.
+func TestRunWithSuiteCommand(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	suite, err := maintest.BuildAll("addsuite", map[string]string{"add": "add"},
+		maintest.Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := suite.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	Run(t, "testdata/suite/*.txt", SuiteCommand{Suite: suite, Name: "add"})
+}
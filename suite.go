@@ -0,0 +1,110 @@
+package maintest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+)
+
+// Suite is a single instrumented executable published under several names in a temporary
+// directory prepended to $PATH, so that code invoking it indirectly by name (exec.LookPath,
+// os/exec with a bare command, go build -toolexec=<name>, a subprocess spawned by the binary
+// under test) resolves to the same binary as a direct Command call. The program itself is
+// expected to dispatch its behavior by inspecting filepath.Base(os.Args[0]) (and/or the
+// MAINTEST_COMMAND environment variable Suite.Command also sets, for cases like
+// -toolexec where a tool is invoked under a fixed name and argv0 alone isn't enough context).
+//
+// Build a Suite with BuildAll.
+type Suite struct {
+	*Exe              // the single built binary shared by every name
+	PathDir string    // temporary directory prepended to $PATH containing one entry per registered name
+
+	names map[string]string // name -> logical command value, also exported as MAINTEST_COMMAND
+}
+
+// BuildAll builds exeName as a single instrumented executable (same as Build, with the same
+// opts) and publishes it under every key of cmds as a same-named symlink (copy on platforms
+// without symlink support) inside a temporary directory added to $PATH. Every name resolves to
+// the same binary; cmds maps each to a logical command value that Suite.Command sets as
+// MAINTEST_COMMAND, so it has an explicit signal to dispatch on alongside os.Args[0] (Environ
+// does not set it, so a name resolved through $PATH sees only os.Args[0]). Every
+// invocation, regardless of name, shares the exe's CoverageDir, so coverage from indirect
+// invocations merges into the same profile as direct Command calls.
+func BuildAll(exeName string, cmds map[string]string, opts ...Option) (*Suite, error) {
+	exe, err := Build(exeName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	pathDir := filepath.Join(exe.binDir, ".bin")
+	if err := os.MkdirAll(pathDir, 0700); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(cmds))
+	for name, value := range cmds {
+		link := filepath.Join(pathDir, name)
+		if runtime.GOOS == "windows" {
+			link += ".exe"
+		}
+		if err := linkOrCopy(exe.Path, link); err != nil {
+			return nil, fmt.Errorf("publish %s: %w", name, err)
+		}
+		names[name] = value
+	}
+
+	return &Suite{Exe: exe, PathDir: pathDir, names: names}, nil
+}
+
+// linkOrCopy publishes src as dst, preferring a symlink and falling back to
+// a copy on platforms (or filesystems) that don't support one.
+func linkOrCopy(src, dst string) error {
+	if err := os.Symlink(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// Command returns a Cmd that runs the shared binary under name's published path, with
+// MAINTEST_COMMAND set to the value cmds[name] held (so the program has an explicit signal to
+// dispatch on, alongside filepath.Base(os.Args[0])) and $PATH extended with PathDir so the
+// command can itself shell out to any other name registered in the suite.
+func (s *Suite) Command(name string, args ...string) *exec.Cmd {
+	link := filepath.Join(s.PathDir, name)
+	if runtime.GOOS == "windows" {
+		link += ".exe"
+	}
+	inv := s.Exe.commandAt(link, args...)
+	inv.Cmd.Env = append(inv.Cmd.Env,
+		"MAINTEST_COMMAND="+s.names[name],
+		"PATH="+s.PathDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return inv.Cmd
+}
+
+// Environ returns os.Environ() with $PATH extended by PathDir, for callers that build their own
+// exec.Cmd (e.g. to invoke a name indirectly through a wrapper script or another subprocess) but
+// still want names in the suite to resolve. GOCOVERDIR is set the same way Command sets it (a
+// fresh invocation subdir per call, or the shared dir under native merging), so those runs'
+// coverage is merged by Finish too.
+func (s *Suite) Environ() []string {
+	covDir := s.CoverageDir
+	if !s.nativeMerge {
+		n := atomic.AddInt32(&s.invCount, 1)
+		covDir = filepath.Join(s.CoverageDir, fmt.Sprintf("inv-%d-%d", n, os.Getpid()))
+		if err := os.MkdirAll(covDir, 0700); err != nil {
+			DebugLog.Printf("mkdir invocation coverage dir %s: %v, falling back to %s", covDir, err, s.CoverageDir)
+			covDir = s.CoverageDir
+		}
+	}
+	return append(os.Environ(), "GOCOVERDIR="+covDir,
+		"PATH="+s.PathDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// Finish merges coverage the same way Exe.Finish does; PathDir lives under the embedded Exe's
+// binDir, so it's removed along with everything else Exe.Finish cleans up.
+func (s *Suite) Finish() error {
+	return s.Exe.Finish()
+}
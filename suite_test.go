@@ -0,0 +1,69 @@
+package maintest
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestBuildAllPublishesSharedBinaryUnderEveryName(t *testing.T) {
+	if _, err := goTool(); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	suite, err := BuildAll("addsuite", map[string]string{
+		"add":    "add",
+		"addalt": "add",
+	}, Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("BuildAll: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := suite.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	for _, name := range []string{"add", "addalt"} {
+		out, err := suite.Command(name, "1", "3").Output()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := string(out[0]); got != "4" {
+			t.Errorf("%s: got %s, want 4", name, got)
+		}
+	}
+}
+
+// TestSuiteCommandResolvedViaPATH exercises the indirect-invocation story Suite exists for: a
+// subprocess (here, a shell) that knows only the bare command name "add" and resolves it itself
+// by searching $PATH, rather than the test calling suite.Command directly.
+func TestSuiteCommandResolvedViaPATH(t *testing.T) {
+	if _, err := goTool(); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	suite, err := BuildAll("addsuite", map[string]string{
+		"add": "add",
+	}, Package("github.com/masp/maintest/example"))
+	if err != nil {
+		t.Fatalf("BuildAll: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := suite.Finish(); err != nil {
+			t.Logf("warning: %v", err)
+		}
+	})
+
+	cmd := exec.Command("sh", "-c", `add "$@"`, "sh", "1", "3")
+	cmd.Env = suite.Environ()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sh -c add: %v", err)
+	}
+	if got := string(out[0]); got != "4" {
+		t.Errorf("got %s, want 4", got)
+	}
+}